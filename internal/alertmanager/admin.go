@@ -0,0 +1,133 @@
+package alertmanager
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// adminAlertmanager is the JSON shape accepted/returned by
+// AdminAlertmanagersHandler - a deliberately small subset of Option that
+// covers what operators need to change without a restart: proxying, timeout,
+// cluster membership and a bearer token.
+type adminAlertmanager struct {
+	Name                  string `json:"name"`
+	URI                   string `json:"uri"`
+	ProxyRequests         bool   `json:"proxyRequests"`
+	RequestTimeoutSeconds int    `json:"requestTimeoutSeconds,omitempty"`
+	Cluster               string `json:"cluster,omitempty"`
+	BearerToken           string `json:"bearerToken,omitempty"`
+}
+
+func (a adminAlertmanager) options() []Option {
+	opts := []Option{WithProxy(a.ProxyRequests)}
+	if a.RequestTimeoutSeconds > 0 {
+		opts = append(opts, WithRequestTimeout(time.Duration(a.RequestTimeoutSeconds)*time.Second))
+	}
+	if a.Cluster != "" {
+		opts = append(opts, WithCluster(a.Cluster))
+	}
+	if a.BearerToken != "" {
+		opts = append(opts, WithBearerToken(a.BearerToken, ""))
+	}
+	return opts
+}
+
+// AdminAlertmanagersHandler serves /api/v1/admin/alertmanagers:
+//
+//	GET    - list every registered upstream
+//	POST   - add a new upstream, or replace an existing one with the same
+//	         name (so ProxyRequests, RequestTimeout and credentials can be
+//	         changed live)
+//	DELETE - remove the upstream named by the "name" query parameter
+//
+// authorized is called on every request before anything else runs, so the
+// caller (main.go, outside this package) can plug in whatever auth scheme
+// protects karma's admin endpoints; a request is rejected with 403 when it
+// returns false.
+func AdminAlertmanagersHandler(authorized func(*http.Request) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorized != nil && !authorized(r) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			handleListAlertmanagers(w, r)
+		case http.MethodPost:
+			handleUpsertAlertmanager(w, r)
+		case http.MethodDelete:
+			handleDeleteAlertmanager(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func handleListAlertmanagers(w http.ResponseWriter, _ *http.Request) {
+	ams := GetAlertmanagers()
+	resp := make([]adminAlertmanager, 0, len(ams))
+	for _, am := range ams {
+		resp = append(resp, adminAlertmanager{
+			Name:                  am.Name,
+			URI:                   am.URI,
+			ProxyRequests:         am.ProxyRequests,
+			RequestTimeoutSeconds: int(am.RequestTimeout / time.Second),
+			Cluster:               am.Cluster,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func handleUpsertAlertmanager(w http.ResponseWriter, r *http.Request) {
+	var payload adminAlertmanager
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if payload.Name == "" || payload.URI == "" {
+		http.Error(w, "name and uri are required", http.StatusBadRequest)
+		return
+	}
+
+	am, err := NewAlertmanager(payload.Name, payload.URI, payload.options()...)
+	if err != nil {
+		http.Error(w, "failed to configure Alertmanager: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	current := GetAlertmanagers()
+	next := make([]*Alertmanager, 0, len(current)+1)
+	for _, existing := range current {
+		if existing.Name != payload.Name {
+			next = append(next, existing)
+		}
+	}
+	next = append(next, am)
+
+	if err := ReplaceAlertmanagers(next); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeJSON(w, http.StatusOK, payload)
+}
+
+func handleDeleteAlertmanager(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "name query parameter is required", http.StatusBadRequest)
+		return
+	}
+	if err := UnregisterAlertmanager(name); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,146 @@
+package alertmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminHandlerRejectsUnauthorized(t *testing.T) {
+	resetRegistry(t)
+	handler := AdminAlertmanagersHandler(func(*http.Request) bool { return false })
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/alertmanagers", nil))
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for an unauthorized request, got %d", rec.Code)
+	}
+}
+
+func TestAdminHandlerListEmpty(t *testing.T) {
+	resetRegistry(t)
+	handler := AdminAlertmanagersHandler(nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/alertmanagers", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var resp []adminAlertmanager
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %s", err)
+	}
+	if len(resp) != 0 {
+		t.Fatalf("expected an empty list, got %+v", resp)
+	}
+}
+
+func TestAdminHandlerPostCreatesUpstream(t *testing.T) {
+	resetRegistry(t)
+	handler := AdminAlertmanagersHandler(nil)
+
+	body := `{"name":"prod","uri":"http://prod.example.com"}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/alertmanagers", bytes.NewBufferString(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if GetAlertmanagerByName("prod") == nil {
+		t.Fatal("expected 'prod' to be registered after POST")
+	}
+}
+
+func TestAdminHandlerPostUpdatesExistingUpstreamInPlace(t *testing.T) {
+	resetRegistry(t)
+	handler := AdminAlertmanagersHandler(nil)
+
+	original := mustNewAlertmanager(t, "prod", "http://prod.example.com")
+	if err := RegisterAlertmanager(original); err != nil {
+		t.Fatalf("register failed: %s", err)
+	}
+
+	body := `{"name":"prod","uri":"http://prod.example.com","requestTimeoutSeconds":30}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/alertmanagers", bytes.NewBufferString(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	updated := GetAlertmanagerByName("prod")
+	if updated == nil {
+		t.Fatal("expected 'prod' to still be registered after update")
+	}
+	if updated == original {
+		t.Fatal("expected the update to swap in a new Alertmanager instance")
+	}
+}
+
+// TestAdminHandlerPostRejectedUpdateLeavesOriginalIntact is a regression test
+// for the bug where updating "prod" with a URI that collides with a
+// different existing upstream used to unregister "prod" first and only then
+// fail to register the replacement, silently deleting it.
+func TestAdminHandlerPostRejectedUpdateLeavesOriginalIntact(t *testing.T) {
+	resetRegistry(t)
+	handler := AdminAlertmanagersHandler(nil)
+
+	prod := mustNewAlertmanager(t, "prod", "http://prod.example.com")
+	other := mustNewAlertmanager(t, "other", "http://other.example.com")
+	if err := RegisterAlertmanager(prod); err != nil {
+		t.Fatalf("register prod failed: %s", err)
+	}
+	if err := RegisterAlertmanager(other); err != nil {
+		t.Fatalf("register other failed: %s", err)
+	}
+
+	// Try to rotate "prod"'s URI to one already used by "other".
+	body := `{"name":"prod","uri":"http://other.example.com"}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/alertmanagers", bytes.NewBufferString(body)))
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a colliding URI, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if GetAlertmanagerByName("prod") == nil {
+		t.Fatal("a rejected update must not delete the original 'prod' upstream")
+	}
+	if GetAlertmanagerByName("other") == nil {
+		t.Fatal("a rejected update must not touch unrelated upstreams either")
+	}
+}
+
+func TestAdminHandlerDeleteExisting(t *testing.T) {
+	resetRegistry(t)
+	handler := AdminAlertmanagersHandler(nil)
+
+	am := mustNewAlertmanager(t, "prod", "http://prod.example.com")
+	if err := RegisterAlertmanager(am); err != nil {
+		t.Fatalf("register failed: %s", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/admin/alertmanagers?name=prod", nil))
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	if GetAlertmanagerByName("prod") != nil {
+		t.Fatal("expected 'prod' to be gone after DELETE")
+	}
+}
+
+func TestAdminHandlerDeleteMissingReturnsNotFound(t *testing.T) {
+	resetRegistry(t)
+	handler := AdminAlertmanagersHandler(nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/admin/alertmanagers?name=missing", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting a name that doesn't exist, got %d", rec.Code)
+	}
+}
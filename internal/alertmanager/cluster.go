@@ -0,0 +1,233 @@
+package alertmanager
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/cloudflare/unsee/internal/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	clusters     = map[string][]string{}
+	clusterNext  = map[string]int{}
+	clustersLock = sync.RWMutex{}
+)
+
+// WithCluster option can be passed to NewAlertmanager in order to mark this
+// upstream as a member of a named HA cluster. Alertmanager instances sharing
+// the same cluster name are pulled from via PullCluster rather than
+// individually: pulls rotate through the cluster's members in registration
+// order, and the results of every member that responds are merged, with
+// duplicate alerts/silences (same fingerprint/ID across peers) collapsed to
+// one copy.
+func WithCluster(clusterName string) Option {
+	return func(am *Alertmanager) error {
+		am.Cluster = clusterName
+		return nil
+	}
+}
+
+// registerClusterMember adds am to its cluster's member list, preserving
+// registration order so pulls can rotate through members deterministically.
+// It's a no-op for upstreams that aren't part of a cluster.
+func registerClusterMember(am *Alertmanager) {
+	if am.Cluster == "" {
+		return
+	}
+	clustersLock.Lock()
+	defer clustersLock.Unlock()
+	for _, name := range clusters[am.Cluster] {
+		if name == am.Name {
+			return
+		}
+	}
+	clusters[am.Cluster] = append(clusters[am.Cluster], am.Name)
+}
+
+// unregisterClusterMember removes am from its cluster's member list, cleaning
+// up the cluster entry entirely once it has no members left.
+func unregisterClusterMember(am *Alertmanager) {
+	if am.Cluster == "" {
+		return
+	}
+	clustersLock.Lock()
+	defer clustersLock.Unlock()
+	members := make([]string, 0, len(clusters[am.Cluster]))
+	for _, name := range clusters[am.Cluster] {
+		if name != am.Name {
+			members = append(members, name)
+		}
+	}
+	if len(members) == 0 {
+		delete(clusters, am.Cluster)
+		delete(clusterNext, am.Cluster)
+	} else {
+		clusters[am.Cluster] = members
+	}
+}
+
+// GetAlertmanagerClusters returns a map of cluster name to the names of all
+// Alertmanager upstreams registered as members of that cluster. Upstreams
+// that aren't part of any cluster are omitted.
+func GetAlertmanagerClusters() map[string][]string {
+	clustersLock.RLock()
+	defer clustersLock.RUnlock()
+	copied := make(map[string][]string, len(clusters))
+	for name, members := range clusters {
+		copied[name] = append([]string{}, members...)
+	}
+	return copied
+}
+
+// clusterPullOrder returns this cluster's members starting from the next
+// preferred one and advances the rotation, so consecutive calls to
+// PullCluster spread pulls across members instead of always hammering the
+// same one first.
+func clusterPullOrder(clusterName string) []string {
+	clustersLock.Lock()
+	defer clustersLock.Unlock()
+	members := clusters[clusterName]
+	if len(members) == 0 {
+		return nil
+	}
+	start := clusterNext[clusterName] % len(members)
+	clusterNext[clusterName] = (start + 1) % len(members)
+
+	ordered := make([]string, len(members))
+	for i := range members {
+		ordered[i] = members[(start+i)%len(members)]
+	}
+	return ordered
+}
+
+// pullUpstream performs the actual HTTP pull for a single upstream,
+// populating its alertGroups/silences fields. It's a variable - rather than
+// a direct am.Pull() call - purely so tests can substitute a fake and
+// exercise PullCluster/PullAll's rotation, failover and dedup logic without
+// a live Alertmanager to talk to.
+var pullUpstream = func(am *Alertmanager) error {
+	return am.Pull()
+}
+
+// PullAll refreshes every registered upstream exactly once. Upstreams that
+// belong to a cluster are refreshed together via PullCluster instead of
+// individually, so a single dead peer doesn't block its siblings and
+// duplicate alerts/silences contributed by more than one peer are collapsed;
+// every other upstream is pulled on its own as before, except discovery
+// parents (configured WithDiscovery), which are never pulled directly since
+// their discovered children are pulled in their place. This is the entry
+// point the periodic pull scheduler should call instead of iterating
+// GetAlertmanagers() and pulling each one individually.
+func PullAll() {
+	clustered := map[string]bool{}
+	for clusterName, members := range GetAlertmanagerClusters() {
+		if err := PullCluster(clusterName); err != nil {
+			log.Errorf("Failed to pull Alertmanager cluster '%s': %s", clusterName, err)
+		}
+		for _, name := range members {
+			clustered[name] = true
+		}
+	}
+
+	for _, am := range GetAlertmanagers() {
+		if clustered[am.Name] || am.discovery != nil {
+			continue
+		}
+		if err := pullUpstream(am); err != nil {
+			log.Errorf("[%s] Pull failed: %s", am.Name, err)
+		}
+	}
+}
+
+// PullCluster refreshes alerts and silences for every member of a named
+// cluster, trying members in rotating preferred order. A member that fails
+// to pull is logged and skipped rather than failing the whole cluster - the
+// call only errors out if every member fails. Alerts and silences collected
+// from the members that did respond are collapsed by fingerprint/ID and
+// written back onto every member, so whichever one a renderer reads from
+// shows the same deduped, cluster-wide view.
+func PullCluster(clusterName string) error {
+	members := clusterPullOrder(clusterName)
+	if len(members) == 0 {
+		return fmt.Errorf("Alertmanager cluster '%s' has no registered members", clusterName)
+	}
+
+	var groups []models.AlertGroup
+	silences := map[string]models.Silence{}
+	var ams []*Alertmanager
+	var all []*Alertmanager
+	var lastErr error
+
+	for _, name := range members {
+		am := GetAlertmanagerByName(name)
+		if am == nil {
+			continue
+		}
+		all = append(all, am)
+		if err := pullUpstream(am); err != nil {
+			lastErr = err
+			log.Warnf("[%s] Pull failed for cluster '%s' member, trying next peer: %s", name, clusterName, err)
+			continue
+		}
+		ams = append(ams, am)
+		am.lock.RLock()
+		groups = mergeAlertGroupsByFingerprint(groups, am.alertGroups)
+		mergeSilencesByID(silences, am.silences)
+		am.lock.RUnlock()
+	}
+
+	if len(ams) == 0 {
+		return fmt.Errorf("all members of Alertmanager cluster '%s' failed to pull, last error: %v", clusterName, lastErr)
+	}
+
+	for _, am := range all {
+		am.lock.Lock()
+		am.alertGroups = groups
+		am.silences = silences
+		am.lock.Unlock()
+	}
+	return nil
+}
+
+// mergeAlertGroupsByFingerprint appends incoming AlertGroups to merged,
+// dropping any alert whose Fingerprint has already been seen in an earlier
+// group - the mechanism that lets two cluster peers report the same firing
+// alert without it appearing twice in the combined view.
+func mergeAlertGroupsByFingerprint(merged []models.AlertGroup, incoming []models.AlertGroup) []models.AlertGroup {
+	seen := map[string]bool{}
+	for _, group := range merged {
+		for _, alert := range group.Alerts {
+			seen[alert.Fingerprint] = true
+		}
+	}
+
+	for _, group := range incoming {
+		deduped := make([]models.Alert, 0, len(group.Alerts))
+		for _, alert := range group.Alerts {
+			if seen[alert.Fingerprint] {
+				continue
+			}
+			seen[alert.Fingerprint] = true
+			deduped = append(deduped, alert)
+		}
+		if len(deduped) == 0 {
+			continue
+		}
+		group.Alerts = deduped
+		merged = append(merged, group)
+	}
+	return merged
+}
+
+// mergeSilencesByID copies every silence from incoming into merged, keyed by
+// ID, so a silence present on more than one cluster peer is kept exactly
+// once.
+func mergeSilencesByID(merged map[string]models.Silence, incoming map[string]models.Silence) {
+	for id, silence := range incoming {
+		if _, found := merged[id]; !found {
+			merged[id] = silence
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package alertmanager
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cloudflare/unsee/internal/models"
+)
+
+// withPullUpstream temporarily swaps the pullUpstream seam for a test fake
+// and returns a func to restore the original.
+func withPullUpstream(t *testing.T, fake func(am *Alertmanager) error) {
+	t.Helper()
+	original := pullUpstream
+	pullUpstream = fake
+	t.Cleanup(func() { pullUpstream = original })
+}
+
+func TestClusterPullOrderRotates(t *testing.T) {
+	resetRegistry(t)
+
+	am1 := mustNewAlertmanager(t, "am1", "http://am1.example.com", WithCluster("ha"))
+	am2 := mustNewAlertmanager(t, "am2", "http://am2.example.com", WithCluster("ha"))
+	registerClusterMember(am1)
+	registerClusterMember(am2)
+
+	first := clusterPullOrder("ha")
+	second := clusterPullOrder("ha")
+	third := clusterPullOrder("ha")
+
+	if first[0] != "am1" || second[0] != "am2" || third[0] != "am1" {
+		t.Fatalf("expected rotation am1,am2,am1 as the preferred member, got %v, %v, %v", first, second, third)
+	}
+}
+
+func TestClusterPullOrderEmptyForUnknownCluster(t *testing.T) {
+	resetRegistry(t)
+	if order := clusterPullOrder("nope"); order != nil {
+		t.Fatalf("expected nil order for an unknown cluster, got %v", order)
+	}
+}
+
+func TestPullClusterMergesAndTolerateOneFailure(t *testing.T) {
+	resetRegistry(t)
+
+	am1 := mustNewAlertmanager(t, "am1", "http://am1.example.com", WithCluster("ha"))
+	am2 := mustNewAlertmanager(t, "am2", "http://am2.example.com", WithCluster("ha"))
+	if err := RegisterAlertmanager(am1); err != nil {
+		t.Fatalf("register am1 failed: %s", err)
+	}
+	if err := RegisterAlertmanager(am2); err != nil {
+		t.Fatalf("register am2 failed: %s", err)
+	}
+
+	withPullUpstream(t, func(am *Alertmanager) error {
+		switch am.Name {
+		case "am1":
+			am.alertGroups = []models.AlertGroup{{Alerts: []models.Alert{{Fingerprint: "shared"}, {Fingerprint: "only-am1"}}}}
+			am.silences = map[string]models.Silence{"sil-1": {}}
+			return nil
+		case "am2":
+			return fmt.Errorf("connection refused")
+		}
+		return fmt.Errorf("unexpected upstream %q", am.Name)
+	})
+
+	if err := PullCluster("ha"); err != nil {
+		t.Fatalf("expected PullCluster to tolerate one failing member, got error: %s", err)
+	}
+
+	if len(am1.alertGroups) != 1 || len(am1.alertGroups[0].Alerts) != 2 {
+		t.Fatalf("expected am1's own groups to survive the merge untouched, got %+v", am1.alertGroups)
+	}
+	if _, found := am1.silences["sil-1"]; !found {
+		t.Fatal("expected am1's silence to be present after merge")
+	}
+
+	// am2 failed to pull, but it should still be left with whatever the
+	// responding peer saw, since it's part of the same cluster view.
+	if len(am2.alertGroups) != 1 {
+		t.Fatalf("expected the failed member to receive the merged view from its peer, got %+v", am2.alertGroups)
+	}
+}
+
+func TestPullClusterFailsWhenEveryMemberFails(t *testing.T) {
+	resetRegistry(t)
+
+	am1 := mustNewAlertmanager(t, "am1", "http://am1.example.com", WithCluster("ha"))
+	if err := RegisterAlertmanager(am1); err != nil {
+		t.Fatalf("register am1 failed: %s", err)
+	}
+
+	withPullUpstream(t, func(am *Alertmanager) error {
+		return fmt.Errorf("boom")
+	})
+
+	if err := PullCluster("ha"); err == nil {
+		t.Fatal("expected an error when every cluster member fails to pull")
+	}
+}
+
+func TestPullClusterUnknownClusterErrors(t *testing.T) {
+	resetRegistry(t)
+	if err := PullCluster("missing"); err == nil {
+		t.Fatal("expected an error pulling a cluster with no registered members")
+	}
+}
+
+func TestMergeAlertGroupsByFingerprintDropsDuplicates(t *testing.T) {
+	merged := []models.AlertGroup{{Alerts: []models.Alert{{Fingerprint: "a"}}}}
+	incoming := []models.AlertGroup{{Alerts: []models.Alert{{Fingerprint: "a"}, {Fingerprint: "b"}}}}
+
+	result := mergeAlertGroupsByFingerprint(merged, incoming)
+
+	var fingerprints []string
+	for _, group := range result {
+		for _, alert := range group.Alerts {
+			fingerprints = append(fingerprints, alert.Fingerprint)
+		}
+	}
+	if len(fingerprints) != 2 {
+		t.Fatalf("expected 'a' to be deduped and 'b' to be kept, got %v", fingerprints)
+	}
+}
+
+func TestMergeSilencesByIDKeepsFirstSeen(t *testing.T) {
+	merged := map[string]models.Silence{"s1": {Comment: "first"}}
+	incoming := map[string]models.Silence{"s1": {Comment: "second"}, "s2": {Comment: "third"}}
+
+	mergeSilencesByID(merged, incoming)
+
+	if merged["s1"].Comment != "first" {
+		t.Fatalf("expected the already-merged silence to win, got %q", merged["s1"].Comment)
+	}
+	if _, found := merged["s2"]; !found {
+		t.Fatal("expected a new silence ID to be added")
+	}
+}
+
+func TestPullAllSkipsClusteredUpstreamsFromIndividualPull(t *testing.T) {
+	resetRegistry(t)
+
+	clustered1 := mustNewAlertmanager(t, "clustered-1", "http://clustered-1.example.com", WithCluster("ha"))
+	clustered2 := mustNewAlertmanager(t, "clustered-2", "http://clustered-2.example.com", WithCluster("ha"))
+	standalone := mustNewAlertmanager(t, "standalone", "http://standalone.example.com")
+	for _, am := range []*Alertmanager{clustered1, clustered2, standalone} {
+		if err := RegisterAlertmanager(am); err != nil {
+			t.Fatalf("register %q failed: %s", am.Name, err)
+		}
+	}
+
+	var pulled []string
+	withPullUpstream(t, func(am *Alertmanager) error {
+		pulled = append(pulled, am.Name)
+		return nil
+	})
+
+	PullAll()
+
+	wasPulled := map[string]bool{}
+	for _, name := range pulled {
+		wasPulled[name] = true
+	}
+	if !wasPulled["standalone"] {
+		t.Fatal("expected the standalone upstream to be pulled individually")
+	}
+	if !wasPulled["clustered-1"] || !wasPulled["clustered-2"] {
+		t.Fatal("expected every cluster member to be pulled via PullCluster")
+	}
+	// PullCluster also goes through pullUpstream, so each clustered member
+	// shows up exactly once - via the cluster path, not a second individual
+	// pull.
+	counts := map[string]int{}
+	for _, name := range pulled {
+		counts[name]++
+	}
+	if counts["clustered-1"] != 1 || counts["clustered-2"] != 1 {
+		t.Fatalf("expected each clustered member to be pulled exactly once, got counts %v", counts)
+	}
+}
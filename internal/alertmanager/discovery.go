@@ -0,0 +1,230 @@
+package alertmanager
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DiscoveryConfig controls how an upstream's concrete Alertmanager instances
+// are discovered and kept in sync at runtime. Exactly one of DNSSD, FileSD
+// or KubernetesSD is expected to be set; resolveDiscoveryTargets picks
+// whichever one is non-nil.
+type DiscoveryConfig struct {
+	DNSSD        *DNSSDConfig
+	FileSD       *FileSDConfig
+	KubernetesSD *KubernetesSDConfig
+}
+
+// DNSSDConfig resolves A or SRV records into a set of targets
+type DNSSDConfig struct {
+	Names           []string
+	Type            string // "A" or "SRV"
+	Port            int    // used when Type is "A"
+	RefreshInterval time.Duration
+}
+
+// FileSDConfig re-reads a JSON target list from disk on every refresh; YAML
+// target files are not parsed
+type FileSDConfig struct {
+	Files           []string
+	RefreshInterval time.Duration
+}
+
+// KubernetesSDConfig watches the Endpoints of a named Service for its ready
+// pod addresses
+type KubernetesSDConfig struct {
+	Namespace       string
+	Service         string
+	Port            int
+	RefreshInterval time.Duration
+}
+
+// discoveryTarget is a single resolved member of a discovered upstream
+type discoveryTarget struct {
+	name string
+	uri  string
+}
+
+// WithDiscovery option can be passed to NewAlertmanager in order to turn a
+// single logical upstream into a dynamic set of Alertmanager instances. The
+// Alertmanager passed to NewAlertmanager acts as the parent: its options
+// (timeout, proxy, auth, TLS) are inherited by every discovered child. The
+// parent has to be registered itself so StartDiscovery can find it, but
+// PullAll never pulls from it directly.
+func WithDiscovery(cfg DiscoveryConfig) Option {
+	return func(am *Alertmanager) error {
+		am.discovery = &cfg
+		return nil
+	}
+}
+
+// StartDiscovery launches the background refresh loop for every registered
+// upstream that was configured with WithDiscovery. It returns immediately;
+// each upstream's targets are refreshed on its own RefreshInterval until
+// stopCh is closed.
+func StartDiscovery(stopCh <-chan struct{}) {
+	for _, am := range GetAlertmanagers() {
+		if am.discovery == nil {
+			continue
+		}
+		go runDiscoveryLoop(am, stopCh)
+	}
+}
+
+func runDiscoveryLoop(parent *Alertmanager, stopCh <-chan struct{}) {
+	interval := discoveryRefreshInterval(parent.discovery)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	refreshDiscoveryTargets(parent)
+	for {
+		select {
+		case <-ticker.C:
+			refreshDiscoveryTargets(parent)
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func discoveryRefreshInterval(cfg *DiscoveryConfig) time.Duration {
+	switch {
+	case cfg.DNSSD != nil && cfg.DNSSD.RefreshInterval > 0:
+		return cfg.DNSSD.RefreshInterval
+	case cfg.FileSD != nil && cfg.FileSD.RefreshInterval > 0:
+		return cfg.FileSD.RefreshInterval
+	case cfg.KubernetesSD != nil && cfg.KubernetesSD.RefreshInterval > 0:
+		return cfg.KubernetesSD.RefreshInterval
+	default:
+		return time.Second * 30
+	}
+}
+
+// refreshDiscoveryTargets resolves the current target set for parent and
+// registers/unregisters the derived child upstreams (named
+// "<parent>/<address>") so they match. Children are created with the
+// parent's options so they inherit its timeout, proxy, auth and TLS setup.
+func refreshDiscoveryTargets(parent *Alertmanager) {
+	targets, err := resolveDiscoveryTargets(parent.discovery)
+	if err != nil {
+		log.Errorf("[%s] Alertmanager discovery refresh failed: %s", parent.Name, err)
+		return
+	}
+
+	wanted := make(map[string]string, len(targets))
+	for _, target := range targets {
+		wanted[fmt.Sprintf("%s/%s", parent.Name, target.name)] = target.uri
+	}
+
+	for name, am := range discoveredChildren(parent.Name) {
+		if _, found := wanted[name]; !found {
+			unregisterDiscoveredAlertmanager(am)
+		}
+	}
+
+	for name, uri := range wanted {
+		child, err := NewAlertmanager(name, uri, parent.discoveredChildOpts()...)
+		if err != nil {
+			log.Errorf("[%s] Failed to configure discovered Alertmanager '%s': %s", parent.Name, name, err)
+			continue
+		}
+		if err := registerOrReplaceAlertmanager(child); err != nil {
+			log.Errorf("[%s] Failed to register discovered Alertmanager '%s': %s", parent.Name, name, err)
+		}
+	}
+}
+
+// discoveredChildOpts returns the options a discovered child Alertmanager
+// should inherit from its parent. The parent's TLS/auth-wrapped
+// baseTransport is reused so children share its connection pool and
+// credentials, but WithCircuitBreaker/WithRetry are re-applied from the
+// parent's settings rather than handing over its already-built
+// httpTransport - that keeps each discovered pod's breaker/retry state
+// isolated, so one flapping pod can't trip the breaker for its siblings.
+func (am *Alertmanager) discoveredChildOpts() []Option {
+	opts := []Option{
+		WithProxy(am.ProxyRequests),
+		WithRequestTimeout(am.RequestTimeout),
+	}
+	if am.baseTransport != nil {
+		opts = append(opts, WithHTTPTransport(am.baseTransport))
+	}
+	if am.Cluster != "" {
+		opts = append(opts, WithCluster(am.Cluster))
+	}
+	if am.circuitBreaker != nil {
+		opts = append(opts, WithCircuitBreaker(*am.circuitBreaker))
+	}
+	if am.retry != nil {
+		opts = append(opts, WithRetry(*am.retry))
+	}
+	return opts
+}
+
+// discoveredChildren returns every currently registered upstream that was
+// derived from parentName via discovery
+func discoveredChildren(parentName string) map[string]*Alertmanager {
+	prefix := parentName + "/"
+	found := map[string]*Alertmanager{}
+	for _, am := range GetAlertmanagers() {
+		if len(am.Name) > len(prefix) && am.Name[:len(prefix)] == prefix {
+			found[am.Name] = am
+		}
+	}
+	return found
+}
+
+// registerOrReplaceAlertmanager behaves like RegisterAlertmanager except that
+// re-registering an upstream under a name that's already known replaces the
+// existing entry in place instead of returning a duplicate-name error. This
+// is what lets concurrent discovery refreshes update a child's target URI
+// without tripping the usual duplicate-URI/duplicate-name checks.
+func registerOrReplaceAlertmanager(am *Alertmanager) error {
+	upstreamsLock.Lock()
+	defer upstreamsLock.Unlock()
+
+	if existing, found := upstreams[am.Name]; found {
+		if existing.URI == am.URI {
+			return nil
+		}
+		upstreams[am.Name] = am
+		unregisterClusterMember(existing)
+		registerClusterMember(am)
+		log.Infof("[%s] Updated discovered Alertmanager source to %s", am.Name, am.URI)
+		return nil
+	}
+
+	for _, existingAM := range upstreams {
+		if existingAM.URI == am.URI && existingAM.Name != am.Name {
+			return fmt.Errorf("Alertmanager upstream '%s' already collects from '%s'", existingAM.Name, existingAM.URI)
+		}
+	}
+	return registerAlertmanagerLocked(am)
+}
+
+// unregisterDiscoveredAlertmanager removes a child upstream that discovery
+// no longer sees in its target set
+func unregisterDiscoveredAlertmanager(am *Alertmanager) {
+	if err := UnregisterAlertmanager(am.Name); err != nil {
+		return
+	}
+	log.Infof("[%s] Removed discovered Alertmanager source, no longer in target set", am.Name)
+}
+
+// resolveDiscoveryTargets resolves cfg into a concrete set of targets.
+// Resolution is wired up per mechanism (dns_sd, file_sd, kubernetes_sd) in
+// their respective lookup helpers; unset mechanisms are skipped.
+func resolveDiscoveryTargets(cfg *DiscoveryConfig) ([]discoveryTarget, error) {
+	switch {
+	case cfg.DNSSD != nil:
+		return lookupDNSSD(cfg.DNSSD)
+	case cfg.FileSD != nil:
+		return lookupFileSD(cfg.FileSD)
+	case cfg.KubernetesSD != nil:
+		return lookupKubernetesSD(cfg.KubernetesSD)
+	default:
+		return nil, fmt.Errorf("discovery config has no dns_sd, file_sd or kubernetes_sd section set")
+	}
+}
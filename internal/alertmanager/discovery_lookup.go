@@ -0,0 +1,171 @@
+package alertmanager
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// lookupDNSSD resolves a DNSSDConfig into a set of targets: Type "SRV" looks
+// up each name as an SRV record and uses the port each record advertises,
+// anything else looks it up as an A/AAAA record and pairs every resolved
+// address with the fixed cfg.Port.
+func lookupDNSSD(cfg *DNSSDConfig) ([]discoveryTarget, error) {
+	targets := []discoveryTarget{}
+	for _, name := range cfg.Names {
+		switch strings.ToUpper(cfg.Type) {
+		case "SRV":
+			_, srvs, err := net.LookupSRV("", "", name)
+			if err != nil {
+				return nil, fmt.Errorf("SRV lookup of '%s' failed: %v", name, err)
+			}
+			for _, srv := range srvs {
+				host := strings.TrimSuffix(srv.Target, ".")
+				targets = append(targets, discoveryTarget{
+					name: fmt.Sprintf("%s:%d", host, srv.Port),
+					uri:  fmt.Sprintf("http://%s:%d", host, srv.Port),
+				})
+			}
+		default:
+			addrs, err := net.LookupHost(name)
+			if err != nil {
+				return nil, fmt.Errorf("A lookup of '%s' failed: %v", name, err)
+			}
+			for _, addr := range addrs {
+				targets = append(targets, discoveryTarget{
+					name: fmt.Sprintf("%s:%d", addr, cfg.Port),
+					uri:  fmt.Sprintf("http://%s:%d", addr, cfg.Port),
+				})
+			}
+		}
+	}
+	return targets, nil
+}
+
+// fileSDTargetGroup is one entry of the JSON array lookupFileSD expects each
+// configured file to contain: a list of "host:port" targets plus labels that
+// are accepted for forward compatibility but not currently used
+type fileSDTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// lookupFileSD reads every configured file (JSON array of target groups) and
+// turns their targets into discovery targets. Files are re-read on every
+// refresh so edits are picked up within RefreshInterval.
+func lookupFileSD(cfg *FileSDConfig) ([]discoveryTarget, error) {
+	targets := []discoveryTarget{}
+	for _, path := range cfg.Files {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file_sd file '%s': %v", path, err)
+		}
+
+		var groups []fileSDTargetGroup
+		if err := json.Unmarshal(content, &groups); err != nil {
+			return nil, fmt.Errorf("failed to parse file_sd file '%s': %v", path, err)
+		}
+
+		for _, group := range groups {
+			for _, addr := range group.Targets {
+				targets = append(targets, discoveryTarget{
+					name: addr,
+					uri:  fmt.Sprintf("http://%s", addr),
+				})
+			}
+		}
+	}
+	return targets, nil
+}
+
+// k8sEndpoints is the minimal subset of the Kubernetes Endpoints API object
+// needed to enumerate ready pod addresses for a Service
+type k8sEndpoints struct {
+	Subsets []struct {
+		Addresses []struct {
+			IP string `json:"ip"`
+		} `json:"addresses"`
+		Ports []struct {
+			Port int32  `json:"port"`
+			Name string `json:"name"`
+		} `json:"ports"`
+	} `json:"subsets"`
+}
+
+// lookupKubernetesSD queries the Endpoints of the configured Service via the
+// in-cluster Kubernetes API, using the pod's mounted service account token.
+// It's meant to run from inside the cluster karma is monitoring; outside of
+// a pod it returns an error rather than guessing at credentials.
+func lookupKubernetesSD(cfg *KubernetesSDConfig) ([]discoveryTarget, error) {
+	host := os.Getenv("KUBERNETES_SERVICE_HOST")
+	port := os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("kubernetes_sd requires running inside a cluster (KUBERNETES_SERVICE_HOST/PORT not set)")
+	}
+
+	const saDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	token, err := ioutil.ReadFile(saDir + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %v", err)
+	}
+	caCert, err := ioutil.ReadFile(saDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account CA cert: %v", err)
+	}
+
+	caCertPool := x509.NewCertPool()
+	if !caCertPool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to parse service account CA cert at '%s/ca.crt'", saDir)
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: caCertPool},
+		},
+	}
+
+	url := fmt.Sprintf("https://%s/api/v1/namespaces/%s/endpoints/%s",
+		net.JoinHostPort(host, port), cfg.Namespace, cfg.Service)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query endpoints for service '%s/%s': %v", cfg.Namespace, cfg.Service, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d querying endpoints for service '%s/%s'", resp.StatusCode, cfg.Namespace, cfg.Service)
+	}
+
+	var endpoints k8sEndpoints
+	if err := json.NewDecoder(resp.Body).Decode(&endpoints); err != nil {
+		return nil, fmt.Errorf("failed to decode endpoints for service '%s/%s': %v", cfg.Namespace, cfg.Service, err)
+	}
+
+	targets := []discoveryTarget{}
+	for _, subset := range endpoints.Subsets {
+		port := cfg.Port
+		for _, p := range subset.Ports {
+			if int(p.Port) == cfg.Port || p.Name == "web" {
+				port = int(p.Port)
+			}
+		}
+		for _, addr := range subset.Addresses {
+			targets = append(targets, discoveryTarget{
+				name: fmt.Sprintf("%s:%d", addr.IP, port),
+				uri:  fmt.Sprintf("http://%s:%d", addr.IP, port),
+			})
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,134 @@
+package alertmanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFileSDFile(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "targets.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write file_sd fixture: %s", err)
+	}
+	return path
+}
+
+func TestLookupFileSDParsesTargets(t *testing.T) {
+	path := writeFileSDFile(t, `[{"targets":["am-0.svc:9093","am-1.svc:9093"],"labels":{"env":"prod"}}]`)
+
+	targets, err := lookupFileSD(&FileSDConfig{Files: []string{path}})
+	if err != nil {
+		t.Fatalf("lookupFileSD returned error: %s", err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 targets, got %d: %+v", len(targets), targets)
+	}
+	if targets[0].name != "am-0.svc:9093" || targets[0].uri != "http://am-0.svc:9093" {
+		t.Fatalf("unexpected first target: %+v", targets[0])
+	}
+}
+
+func TestLookupFileSDRejectsMalformedJSON(t *testing.T) {
+	path := writeFileSDFile(t, `not json`)
+
+	if _, err := lookupFileSD(&FileSDConfig{Files: []string{path}}); err == nil {
+		t.Fatal("expected an error parsing malformed file_sd JSON")
+	}
+}
+
+func TestLookupFileSDErrorsOnMissingFile(t *testing.T) {
+	if _, err := lookupFileSD(&FileSDConfig{Files: []string{"/does/not/exist.json"}}); err == nil {
+		t.Fatal("expected an error reading a missing file_sd file")
+	}
+}
+
+func TestRefreshDiscoveryTargetsRegistersAndRemovesChildren(t *testing.T) {
+	resetRegistry(t)
+
+	path := writeFileSDFile(t, `[{"targets":["am-0.svc:9093"]}]`)
+	parent := mustNewAlertmanager(t, "parent", "http://parent.example.com", WithDiscovery(DiscoveryConfig{
+		FileSD: &FileSDConfig{Files: []string{path}},
+	}))
+
+	refreshDiscoveryTargets(parent)
+
+	children := discoveredChildren("parent")
+	if len(children) != 1 {
+		t.Fatalf("expected 1 discovered child, got %d: %+v", len(children), children)
+	}
+	if _, found := children["parent/am-0.svc:9093"]; !found {
+		t.Fatalf("expected child named 'parent/am-0.svc:9093', got %+v", children)
+	}
+
+	// Rewrite the target file with a different target set and refresh again:
+	// the stale child should be unregistered and the new one registered.
+	if err := os.WriteFile(path, []byte(`[{"targets":["am-1.svc:9093"]}]`), 0o644); err != nil {
+		t.Fatalf("failed to rewrite file_sd fixture: %s", err)
+	}
+	refreshDiscoveryTargets(parent)
+
+	children = discoveredChildren("parent")
+	if len(children) != 1 {
+		t.Fatalf("expected 1 discovered child after refresh, got %d: %+v", len(children), children)
+	}
+	if _, found := children["parent/am-1.svc:9093"]; !found {
+		t.Fatalf("expected the stale child to be replaced by 'parent/am-1.svc:9093', got %+v", children)
+	}
+}
+
+func TestRegisterOrReplaceAlertmanagerIsNoopForSameURI(t *testing.T) {
+	resetRegistry(t)
+
+	am := mustNewAlertmanager(t, "parent/am-0", "http://am-0.example.com")
+	if err := RegisterAlertmanager(am); err != nil {
+		t.Fatalf("register failed: %s", err)
+	}
+
+	same := mustNewAlertmanager(t, "parent/am-0", "http://am-0.example.com")
+	if err := registerOrReplaceAlertmanager(same); err != nil {
+		t.Fatalf("expected a same-name-same-URI re-register to be a no-op, got error: %s", err)
+	}
+	if GetAlertmanagerByName("parent/am-0") != am {
+		t.Fatal("expected the original instance to be left in place for a no-op re-register")
+	}
+}
+
+func TestRegisterOrReplaceAlertmanagerReplacesOnURIChange(t *testing.T) {
+	resetRegistry(t)
+
+	am := mustNewAlertmanager(t, "parent/am-0", "http://am-0-old.example.com")
+	if err := RegisterAlertmanager(am); err != nil {
+		t.Fatalf("register failed: %s", err)
+	}
+
+	replacement := mustNewAlertmanager(t, "parent/am-0", "http://am-0-new.example.com")
+	if err := registerOrReplaceAlertmanager(replacement); err != nil {
+		t.Fatalf("expected the replacement to be accepted, got error: %s", err)
+	}
+	if GetAlertmanagerByName("parent/am-0") != replacement {
+		t.Fatal("expected the registry to hold the replacement instance")
+	}
+}
+
+func TestRegisterOrReplaceAlertmanagerRejectsURICollisionWithDifferentName(t *testing.T) {
+	resetRegistry(t)
+
+	existing := mustNewAlertmanager(t, "parent/am-0", "http://am-0.example.com")
+	if err := RegisterAlertmanager(existing); err != nil {
+		t.Fatalf("register failed: %s", err)
+	}
+
+	colliding := mustNewAlertmanager(t, "parent/am-1", "http://am-0.example.com")
+	if err := registerOrReplaceAlertmanager(colliding); err == nil {
+		t.Fatal("expected an error registering a new name with a URI already used by a different upstream")
+	}
+	if GetAlertmanagerByName("parent/am-0") != existing {
+		t.Fatal("a rejected registration must not disturb the existing upstream")
+	}
+	if GetAlertmanagerByName("parent/am-1") != nil {
+		t.Fatal("a rejected registration must not leave the new name registered")
+	}
+}
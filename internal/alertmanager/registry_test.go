@@ -0,0 +1,155 @@
+package alertmanager
+
+import (
+	"sync"
+	"testing"
+)
+
+// resetRegistry clears the package-level upstream/cluster registries before
+// and after a test so tests don't leak state into each other.
+func resetRegistry(t *testing.T) {
+	t.Helper()
+	upstreamsLock.Lock()
+	upstreams = map[string]*Alertmanager{}
+	upstreamsLock.Unlock()
+	clustersLock.Lock()
+	clusters = map[string][]string{}
+	clusterNext = map[string]int{}
+	clustersLock.Unlock()
+}
+
+func mustNewAlertmanager(t *testing.T, name, uri string, opts ...Option) *Alertmanager {
+	t.Helper()
+	am, err := NewAlertmanager(name, uri, opts...)
+	if err != nil {
+		t.Fatalf("NewAlertmanager(%q, %q) returned error: %s", name, uri, err)
+	}
+	return am
+}
+
+func TestRegisterAlertmanagerRejectsDuplicateName(t *testing.T) {
+	resetRegistry(t)
+
+	am1 := mustNewAlertmanager(t, "prod", "http://prod.example.com")
+	if err := RegisterAlertmanager(am1); err != nil {
+		t.Fatalf("first registration failed: %s", err)
+	}
+
+	am2 := mustNewAlertmanager(t, "prod", "http://other.example.com")
+	if err := RegisterAlertmanager(am2); err == nil {
+		t.Fatal("expected an error registering a duplicate name, got nil")
+	}
+}
+
+func TestRegisterAlertmanagerRejectsDuplicateURI(t *testing.T) {
+	resetRegistry(t)
+
+	am1 := mustNewAlertmanager(t, "prod-1", "http://prod.example.com")
+	if err := RegisterAlertmanager(am1); err != nil {
+		t.Fatalf("first registration failed: %s", err)
+	}
+
+	am2 := mustNewAlertmanager(t, "prod-2", "http://prod.example.com")
+	if err := RegisterAlertmanager(am2); err == nil {
+		t.Fatal("expected an error registering a duplicate URI, got nil")
+	}
+}
+
+func TestUnregisterAlertmanager(t *testing.T) {
+	resetRegistry(t)
+
+	am := mustNewAlertmanager(t, "prod", "http://prod.example.com")
+	if err := RegisterAlertmanager(am); err != nil {
+		t.Fatalf("registration failed: %s", err)
+	}
+
+	if err := UnregisterAlertmanager("prod"); err != nil {
+		t.Fatalf("unregister failed: %s", err)
+	}
+	if GetAlertmanagerByName("prod") != nil {
+		t.Fatal("expected 'prod' to be gone after UnregisterAlertmanager")
+	}
+	if err := UnregisterAlertmanager("prod"); err == nil {
+		t.Fatal("expected an error unregistering a name that no longer exists")
+	}
+}
+
+func TestReplaceAlertmanagersSwapsAtomically(t *testing.T) {
+	resetRegistry(t)
+
+	old := mustNewAlertmanager(t, "prod", "http://prod.example.com")
+	if err := RegisterAlertmanager(old); err != nil {
+		t.Fatalf("registration failed: %s", err)
+	}
+
+	replacement := mustNewAlertmanager(t, "prod-2", "http://prod-2.example.com")
+	if err := ReplaceAlertmanagers([]*Alertmanager{replacement}); err != nil {
+		t.Fatalf("ReplaceAlertmanagers failed: %s", err)
+	}
+
+	if GetAlertmanagerByName("prod") != nil {
+		t.Fatal("expected 'prod' to be gone after ReplaceAlertmanagers")
+	}
+	if GetAlertmanagerByName("prod-2") == nil {
+		t.Fatal("expected 'prod-2' to be present after ReplaceAlertmanagers")
+	}
+}
+
+func TestReplaceAlertmanagersRejectsDuplicatesWithoutMutatingRegistry(t *testing.T) {
+	resetRegistry(t)
+
+	kept := mustNewAlertmanager(t, "prod", "http://prod.example.com")
+	if err := RegisterAlertmanager(kept); err != nil {
+		t.Fatalf("registration failed: %s", err)
+	}
+
+	dup1 := mustNewAlertmanager(t, "dup", "http://dup-1.example.com")
+	dup2 := mustNewAlertmanager(t, "dup", "http://dup-2.example.com")
+	if err := ReplaceAlertmanagers([]*Alertmanager{dup1, dup2}); err == nil {
+		t.Fatal("expected an error replacing with a duplicate name, got nil")
+	}
+
+	if GetAlertmanagerByName("prod") == nil {
+		t.Fatal("a rejected ReplaceAlertmanagers call must not discard the existing registry")
+	}
+}
+
+// TestRegistryConcurrentAccess exercises RegisterAlertmanager,
+// UnregisterAlertmanager and GetAlertmanagers from many goroutines at once.
+// Run with -race to catch data races on the upstreams map.
+func TestRegistryConcurrentAccess(t *testing.T) {
+	resetRegistry(t)
+
+	const workers = 20
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func(i int) {
+			defer wg.Done()
+			am, err := NewAlertmanager(nameFor(i), uriFor(i))
+			if err != nil {
+				t.Errorf("worker %d: NewAlertmanager failed: %s", i, err)
+				return
+			}
+			if err := RegisterAlertmanager(am); err != nil {
+				t.Errorf("worker %d: register failed: %s", i, err)
+				return
+			}
+			_ = GetAlertmanagers()
+			_ = GetAlertmanagerByName(nameFor(i))
+			if err := UnregisterAlertmanager(nameFor(i)); err != nil {
+				t.Errorf("worker %d: unregister failed: %s", i, err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if len(GetAlertmanagers()) != 0 {
+		t.Fatalf("expected an empty registry once all workers finished, got %d entries", len(GetAlertmanagers()))
+	}
+}
+
+func nameFor(i int) string { return "worker-" + string(rune('a'+i)) }
+func uriFor(i int) string  { return "http://worker-" + string(rune('a'+i)) + ".example.com" }
@@ -0,0 +1,52 @@
+package alertmanager
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ConfigLoader builds the full set of Alertmanager upstreams that should be
+// registered, normally by re-reading and re-parsing karma's YAML config. It's
+// supplied by the caller rather than read here since config parsing lives
+// outside this package.
+type ConfigLoader func() ([]*Alertmanager, error)
+
+// ReloadFromConfig runs load and, on success, atomically swaps the current
+// set of upstreams for whatever it returned via ReplaceAlertmanagers. Pulls
+// already in flight against the old upstreams keep running against the
+// *Alertmanager values they hold; only the registry used to start new pulls
+// changes.
+func ReloadFromConfig(load ConfigLoader) error {
+	ams, err := load()
+	if err != nil {
+		return err
+	}
+	return ReplaceAlertmanagers(ams)
+}
+
+// WatchSIGHUP starts a goroutine that calls ReloadFromConfig every time the
+// process receives SIGHUP, until stopCh is closed. This is what lets
+// operators add an Alertmanager or rotate a bearer token by editing the
+// config file and sending `kill -HUP` instead of restarting karma.
+func WatchSIGHUP(load ConfigLoader, stopCh <-chan struct{}) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(sigCh)
+		for {
+			select {
+			case <-sigCh:
+				log.Info("Got SIGHUP, reloading Alertmanager upstreams")
+				if err := ReloadFromConfig(load); err != nil {
+					log.Errorf("Failed to reload Alertmanager upstreams: %s", err)
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,193 @@
+package alertmanager
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CircuitBreakerSettings configures WithCircuitBreaker
+type CircuitBreakerSettings struct {
+	// FailureThreshold is the number of consecutive request failures (network
+	// errors or 5xx responses) that trip the breaker open
+	FailureThreshold int
+	// CooldownInterval is how long the breaker stays open, short-circuiting
+	// requests, before it lets requests through again; the failure count isn't
+	// reset by the cooldown, so the next failed request reopens it at once
+	CooldownInterval time.Duration
+}
+
+// WithCircuitBreaker option can be passed to NewAlertmanager in order to
+// short-circuit requests to this upstream for CooldownInterval once
+// FailureThreshold consecutive failures have been observed, so a single
+// flaky Alertmanager can't stall every pull (or every proxied silence
+// request) for the full RequestTimeout. The breaker is applied around any
+// WithRetry wrapping (regardless of the order the two options are passed in)
+// so an open breaker fails a request immediately instead of burning a full
+// round of retries first.
+func WithCircuitBreaker(settings CircuitBreakerSettings) Option {
+	return func(am *Alertmanager) error {
+		if settings.FailureThreshold < 1 {
+			return fmt.Errorf("circuit breaker FailureThreshold must be at least 1, got %d", settings.FailureThreshold)
+		}
+		am.circuitBreaker = &settings
+		return nil
+	}
+}
+
+// RetrySettings configures WithRetry
+type RetrySettings struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// WithRetry option can be passed to NewAlertmanager in order to retry
+// requests that fail with a network error or a 5xx response, using
+// exponential backoff with jitter between attempts.
+func WithRetry(settings RetrySettings) Option {
+	return func(am *Alertmanager) error {
+		if settings.MaxAttempts < 1 {
+			return fmt.Errorf("retry MaxAttempts must be at least 1, got %d", settings.MaxAttempts)
+		}
+		am.retry = &settings
+		return nil
+	}
+}
+
+// buildResilienceTransport wraps am.httpTransport with the configured retry
+// and circuit breaker layers, in that fixed order (circuit breaker
+// outermost) regardless of which order WithRetry/WithCircuitBreaker were
+// passed in. It must run after TLS and auth wrapping so neither of those
+// ever has to see through a resilience wrapper.
+func (am *Alertmanager) buildResilienceTransport() {
+	if am.retry != nil {
+		am.httpTransport = &retryRoundTripper{
+			settings: *am.retry,
+			next:     wrapOrDefault(am.httpTransport),
+		}
+	}
+	if am.circuitBreaker != nil {
+		am.httpTransport = &circuitBreakerRoundTripper{
+			settings: *am.circuitBreaker,
+			next:     wrapOrDefault(am.httpTransport),
+			am:       am,
+		}
+	}
+}
+
+// labelValueErrorsCircuitOpen is the metrics.errors bucket incremented every
+// time a request is short-circuited by an open circuit breaker, alongside
+// the existing labelValueErrorsAlerts/labelValueErrorsSilences buckets
+const labelValueErrorsCircuitOpen = "circuit-open"
+
+// recordError increments am's error counter for label, used by the circuit
+// breaker to report short-circuited requests through the same metrics the
+// pull loop already reports alert/silence fetch failures through
+func (am *Alertmanager) recordError(label string) {
+	am.lock.Lock()
+	defer am.lock.Unlock()
+	if am.metrics.errors == nil {
+		am.metrics.errors = map[string]float64{}
+	}
+	am.metrics.errors[label]++
+}
+
+func wrapOrDefault(rt http.RoundTripper) http.RoundTripper {
+	if rt != nil {
+		return rt
+	}
+	return http.DefaultTransport
+}
+
+// circuitBreakerState is the state of a single upstream's breaker
+type circuitBreakerState struct {
+	mu                  sync.Mutex
+	consecutiveFailures int
+	openUntil           time.Time
+}
+
+type circuitBreakerRoundTripper struct {
+	settings CircuitBreakerSettings
+	next     http.RoundTripper
+	am       *Alertmanager
+	state    circuitBreakerState
+}
+
+func (rt *circuitBreakerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.state.mu.Lock()
+	if time.Now().Before(rt.state.openUntil) {
+		rt.state.mu.Unlock()
+		rt.am.recordError(labelValueErrorsCircuitOpen)
+		return nil, fmt.Errorf("circuit breaker open for '%s', cooling down", rt.am.Name)
+	}
+	rt.state.mu.Unlock()
+
+	resp, err := rt.next.RoundTrip(req)
+
+	rt.state.mu.Lock()
+	defer rt.state.mu.Unlock()
+	if err != nil || (resp != nil && resp.StatusCode >= 500) {
+		rt.state.consecutiveFailures++
+		if rt.state.consecutiveFailures >= rt.settings.FailureThreshold {
+			rt.state.openUntil = time.Now().Add(rt.settings.CooldownInterval)
+		}
+	} else {
+		rt.state.consecutiveFailures = 0
+		rt.state.openUntil = time.Time{}
+	}
+	return resp, err
+}
+
+type retryRoundTripper struct {
+	settings RetrySettings
+	next     http.RoundTripper
+}
+
+func (rt *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	var err error
+
+	delay := rt.settings.InitialDelay
+	for attempt := 1; attempt <= rt.settings.MaxAttempts; attempt++ {
+		attemptReq := req
+		if req.Body != nil && attempt > 1 {
+			// req.Body was already read (and closed) by the previous attempt's
+			// RoundTrip, so every retry after the first needs a fresh copy
+			if req.GetBody == nil {
+				return nil, fmt.Errorf("request body for '%s' isn't replayable, can't retry", req.URL)
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, fmt.Errorf("failed to rewind request body for retry: %v", bodyErr)
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err = rt.next.RoundTrip(attemptReq)
+		if err == nil && (resp == nil || resp.StatusCode < 500) {
+			return resp, nil
+		}
+		if attempt == rt.settings.MaxAttempts {
+			break
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		sleep := delay/2 + time.Duration(rand.Int63n(int64(delay)+1))
+		select {
+		case <-time.After(sleep):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		delay *= 2
+		if rt.settings.MaxDelay > 0 && delay > rt.settings.MaxDelay {
+			delay = rt.settings.MaxDelay
+		}
+	}
+	return resp, err
+}
@@ -0,0 +1,216 @@
+package alertmanager
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// stubRoundTripper returns a scripted sequence of responses/errors, one per
+// call, and records every request it saw for assertions.
+type stubRoundTripper struct {
+	responses []stubResponse
+	calls     int
+	seenBody  []string
+}
+
+type stubResponse struct {
+	status int
+	err    error
+}
+
+func (s *stubRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		body, _ := ioutil.ReadAll(req.Body)
+		s.seenBody = append(s.seenBody, string(body))
+	} else {
+		s.seenBody = append(s.seenBody, "")
+	}
+
+	resp := s.responses[s.calls]
+	s.calls++
+	if resp.err != nil {
+		return nil, resp.err
+	}
+	return &http.Response{
+		StatusCode: resp.status,
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+	}, nil
+}
+
+func newTestAlertmanager(t *testing.T) *Alertmanager {
+	t.Helper()
+	return &Alertmanager{Name: "test"}
+}
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	stub := &stubRoundTripper{responses: []stubResponse{
+		{err: errors.New("boom")},
+		{err: errors.New("boom")},
+		{status: http.StatusOK},
+	}}
+	am := newTestAlertmanager(t)
+	rt := &circuitBreakerRoundTripper{
+		settings: CircuitBreakerSettings{FailureThreshold: 2, CooldownInterval: time.Minute},
+		next:     stub,
+		am:       am,
+	}
+
+	req := httpGetRequest(t)
+
+	if _, err := rt.RoundTrip(req); err == nil {
+		t.Fatal("expected the first failing request to return an error")
+	}
+	if _, err := rt.RoundTrip(req); err == nil {
+		t.Fatal("expected the second failing request to return an error")
+	}
+
+	// Breaker should now be open: a third call must short-circuit without
+	// reaching the underlying transport.
+	if _, err := rt.RoundTrip(req); err == nil {
+		t.Fatal("expected the breaker to be open and return an error")
+	}
+	if stub.calls != 2 {
+		t.Fatalf("expected the underlying transport to be called twice before the breaker opened, got %d calls", stub.calls)
+	}
+}
+
+func TestCircuitBreakerResetsOnSuccess(t *testing.T) {
+	stub := &stubRoundTripper{responses: []stubResponse{
+		{err: errors.New("boom")},
+		{status: http.StatusOK},
+		{err: errors.New("boom")},
+	}}
+	am := newTestAlertmanager(t)
+	rt := &circuitBreakerRoundTripper{
+		settings: CircuitBreakerSettings{FailureThreshold: 2, CooldownInterval: time.Minute},
+		next:     stub,
+		am:       am,
+	}
+	req := httpGetRequest(t)
+
+	rt.RoundTrip(req) // failure #1
+	rt.RoundTrip(req) // success resets the counter
+
+	if rt.state.consecutiveFailures != 0 {
+		t.Fatalf("expected consecutiveFailures to reset to 0 after a success, got %d", rt.state.consecutiveFailures)
+	}
+
+	// One more failure shouldn't be enough to open the breaker since the
+	// counter was reset.
+	if _, err := rt.RoundTrip(req); err == nil {
+		t.Fatal("expected this call to surface the underlying transport error")
+	}
+	if !rt.state.openUntil.IsZero() {
+		t.Fatal("expected the breaker to still be closed after a single failure post-reset")
+	}
+}
+
+func TestRetryRoundTripperRetriesOn5xx(t *testing.T) {
+	stub := &stubRoundTripper{responses: []stubResponse{
+		{status: http.StatusBadGateway},
+		{status: http.StatusOK},
+	}}
+	rt := &retryRoundTripper{
+		settings: RetrySettings{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond},
+		next:     stub,
+	}
+
+	resp, err := rt.RoundTrip(httpGetRequest(t))
+	if err != nil {
+		t.Fatalf("expected the retry to eventually succeed, got error: %s", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected a 200 after retrying, got %d", resp.StatusCode)
+	}
+	if stub.calls != 2 {
+		t.Fatalf("expected exactly 2 attempts, got %d", stub.calls)
+	}
+}
+
+func TestRetryRoundTripperDoesNotRetryOn4xx(t *testing.T) {
+	stub := &stubRoundTripper{responses: []stubResponse{
+		{status: http.StatusNotFound},
+	}}
+	rt := &retryRoundTripper{
+		settings: RetrySettings{MaxAttempts: 3, InitialDelay: time.Millisecond},
+		next:     stub,
+	}
+
+	resp, err := rt.RoundTrip(httpGetRequest(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected the 404 to be returned as-is, got %d", resp.StatusCode)
+	}
+	if stub.calls != 1 {
+		t.Fatalf("expected a 4xx to not be retried, got %d attempts", stub.calls)
+	}
+}
+
+func TestRetryRoundTripperRewindsBodyOnRetry(t *testing.T) {
+	stub := &stubRoundTripper{responses: []stubResponse{
+		{status: http.StatusServiceUnavailable},
+		{status: http.StatusOK},
+	}}
+	rt := &retryRoundTripper{
+		settings: RetrySettings{MaxAttempts: 2, InitialDelay: time.Millisecond},
+		next:     stub,
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "http://upstream.example.com/api/v2/silences", bytes.NewBufferString(`{"id":"abc"}`))
+	if err != nil {
+		t.Fatalf("failed to build request: %s", err)
+	}
+
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("expected the retried request to succeed, got: %s", err)
+	}
+	if len(stub.seenBody) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(stub.seenBody))
+	}
+	for i, body := range stub.seenBody {
+		if body != `{"id":"abc"}` {
+			t.Fatalf("attempt %d saw body %q, expected the original payload on every attempt", i+1, body)
+		}
+	}
+}
+
+func TestRetryRoundTripperFailsFastOnUnreplayableBody(t *testing.T) {
+	stub := &stubRoundTripper{responses: []stubResponse{
+		{status: http.StatusServiceUnavailable},
+		{status: http.StatusOK},
+	}}
+	rt := &retryRoundTripper{
+		settings: RetrySettings{MaxAttempts: 2, InitialDelay: time.Millisecond},
+		next:     stub,
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "http://upstream.example.com/api/v2/silences", bytes.NewBufferString(`{"id":"abc"}`))
+	if err != nil {
+		t.Fatalf("failed to build request: %s", err)
+	}
+	// Simulate a body that can't be re-read, e.g. a caller-supplied io.Reader
+	// without GetBody wired up.
+	req.GetBody = nil
+
+	if _, err := rt.RoundTrip(req); err == nil {
+		t.Fatal("expected an error when the body can't be rewound for a retry")
+	}
+	if stub.calls != 1 {
+		t.Fatalf("expected only the first attempt to run, got %d calls", stub.calls)
+	}
+}
+
+func httpGetRequest(t *testing.T) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, "http://upstream.example.com/api/v2/alerts", nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %s", err)
+	}
+	return req
+}
@@ -0,0 +1,160 @@
+package alertmanager
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// TLSConfig carries the per-upstream TLS settings accepted by WithTLSConfig:
+// an optional CA bundle to validate the upstream's certificate against, an
+// optional client certificate/key pair for mTLS, and the usual ServerName/
+// InsecureSkipVerify escape hatches.
+type TLSConfig struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// WithTLSConfig option can be passed to NewAlertmanager in order to set
+// per-upstream TLS settings (CA file, client cert/key, server name,
+// InsecureSkipVerify) used both for pulling from this Alertmanager and for
+// proxied silence requests made on its behalf.
+func WithTLSConfig(cfg *TLSConfig) Option {
+	return func(am *Alertmanager) error {
+		tlsClientConfig := &tls.Config{
+			ServerName:         cfg.ServerName,
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+		}
+
+		if cfg.CAFile != "" {
+			caCert, err := ioutil.ReadFile(cfg.CAFile)
+			if err != nil {
+				return fmt.Errorf("failed to read CA file '%s': %v", cfg.CAFile, err)
+			}
+			caCertPool := x509.NewCertPool()
+			if !caCertPool.AppendCertsFromPEM(caCert) {
+				return fmt.Errorf("failed to parse CA file '%s'", cfg.CAFile)
+			}
+			tlsClientConfig.RootCAs = caCertPool
+		}
+
+		if cfg.CertFile != "" || cfg.KeyFile != "" {
+			cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+			if err != nil {
+				return fmt.Errorf("failed to load client certificate '%s' / '%s': %v", cfg.CertFile, cfg.KeyFile, err)
+			}
+			tlsClientConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		am.tlsConfig = tlsClientConfig
+		return nil
+	}
+}
+
+// WithBasicAuth option can be passed to NewAlertmanager in order to send an
+// HTTP basic auth header with every request made to this upstream, including
+// proxied silence requests.
+func WithBasicAuth(username, password string) Option {
+	return func(am *Alertmanager) error {
+		am.authTransport = &basicAuthRoundTripper{
+			username: username,
+			password: password,
+			next:     am.authTransport,
+		}
+		return nil
+	}
+}
+
+// WithBearerToken option can be passed to NewAlertmanager in order to send a
+// bearer token with every request made to this upstream. Exactly one of
+// token or tokenFile should be set; tokenFile is re-read on every request so
+// a rotated token (for example a Kubernetes service account token) is picked
+// up without restarting karma.
+func WithBearerToken(token, tokenFile string) Option {
+	return func(am *Alertmanager) error {
+		am.authTransport = &bearerTokenRoundTripper{
+			token:     token,
+			tokenFile: tokenFile,
+			next:      am.authTransport,
+		}
+		return nil
+	}
+}
+
+// bindAuthTransportChain walks a chain of auth round trippers built up by
+// WithBasicAuth/WithBearerToken (in the order those options were applied)
+// and points the innermost one at base, so the chain ultimately falls
+// through to the upstream's configured (and possibly TLS-wrapped) transport.
+func bindAuthTransportChain(rt http.RoundTripper, base http.RoundTripper) {
+	for {
+		switch t := rt.(type) {
+		case *basicAuthRoundTripper:
+			if t.next == nil {
+				t.next = base
+				return
+			}
+			rt = t.next
+		case *bearerTokenRoundTripper:
+			if t.next == nil {
+				t.next = base
+				return
+			}
+			rt = t.next
+		default:
+			return
+		}
+	}
+}
+
+// basicAuthRoundTripper injects an HTTP basic auth header into every request
+type basicAuthRoundTripper struct {
+	username string
+	password string
+	next     http.RoundTripper
+}
+
+func (rt *basicAuthRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.SetBasicAuth(rt.username, rt.password)
+	return rt.nextTransport().RoundTrip(req)
+}
+
+func (rt *basicAuthRoundTripper) nextTransport() http.RoundTripper {
+	if rt.next != nil {
+		return rt.next
+	}
+	return http.DefaultTransport
+}
+
+// bearerTokenRoundTripper injects a bearer token Authorization header into
+// every request, re-reading tokenFile (when set) on each call
+type bearerTokenRoundTripper struct {
+	token     string
+	tokenFile string
+	next      http.RoundTripper
+}
+
+func (rt *bearerTokenRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	token := rt.token
+	if rt.tokenFile != "" {
+		content, err := ioutil.ReadFile(rt.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bearer token file '%s': %v", rt.tokenFile, err)
+		}
+		token = strings.TrimSpace(string(content))
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return rt.nextTransport().RoundTrip(req)
+}
+
+func (rt *bearerTokenRoundTripper) nextTransport() http.RoundTripper {
+	if rt.next != nil {
+		return rt.next
+	}
+	return http.DefaultTransport
+}
@@ -16,7 +16,8 @@ import (
 type Option func(am *Alertmanager) error
 
 var (
-	upstreams = map[string]*Alertmanager{}
+	upstreams     = map[string]*Alertmanager{}
+	upstreamsLock = sync.RWMutex{}
 )
 
 // NewAlertmanager creates a new Alertmanager instance
@@ -45,6 +46,31 @@ func NewAlertmanager(name, uri string, opts ...Option) (*Alertmanager, error) {
 		}
 	}
 
+	if am.tlsConfig != nil {
+		httpTransport, ok := am.httpTransport.(*http.Transport)
+		if !ok {
+			if am.httpTransport != nil {
+				return nil, fmt.Errorf("WithTLSConfig can't be combined with a custom WithHTTPTransport of type %T, which doesn't expose a *http.Transport to apply TLS settings to", am.httpTransport)
+			}
+			httpTransport = http.DefaultTransport.(*http.Transport).Clone()
+		}
+		httpTransport.TLSClientConfig = am.tlsConfig
+		am.httpTransport = httpTransport
+	}
+
+	if am.authTransport != nil {
+		bindAuthTransportChain(am.authTransport, am.httpTransport)
+		am.httpTransport = am.authTransport
+	}
+
+	// baseTransport is the TLS/auth-wrapped transport before any resilience
+	// wrapping is layered on top of it. Discovered children (chunk0-3) reuse
+	// this instead of am.httpTransport so each child gets its own circuit
+	// breaker/retry RoundTripper - and state - rather than sharing the
+	// parent's.
+	am.baseTransport = am.httpTransport
+	am.buildResilienceTransport()
+
 	var err error
 	am.transport, err = transport.NewTransport(am.URI, am.RequestTimeout, am.httpTransport)
 	if err != nil {
@@ -57,6 +83,14 @@ func NewAlertmanager(name, uri string, opts ...Option) (*Alertmanager, error) {
 // RegisterAlertmanager will add an Alertmanager instance to the list of
 // instances used when pulling alerts from upstreams
 func RegisterAlertmanager(am *Alertmanager) error {
+	upstreamsLock.Lock()
+	defer upstreamsLock.Unlock()
+	return registerAlertmanagerLocked(am)
+}
+
+// registerAlertmanagerLocked does the actual work of RegisterAlertmanager
+// and must be called with upstreamsLock already held for writing
+func registerAlertmanagerLocked(am *Alertmanager) error {
 	if _, found := upstreams[am.Name]; found {
 		return fmt.Errorf("Alertmanager upstream '%s' already exist", am.Name)
 	}
@@ -67,13 +101,75 @@ func RegisterAlertmanager(am *Alertmanager) error {
 		}
 	}
 	upstreams[am.Name] = am
-	log.Infof("[%s] Configured Alertmanager source at %s (proxied: %v)", am.Name, am.URI, am.ProxyRequests)
+	registerClusterMember(am)
+	if am.Cluster != "" {
+		log.Infof("[%s] Configured Alertmanager source at %s (proxied: %v, cluster: %s)", am.Name, am.URI, am.ProxyRequests, am.Cluster)
+	} else {
+		log.Infof("[%s] Configured Alertmanager source at %s (proxied: %v)", am.Name, am.URI, am.ProxyRequests)
+	}
+	return nil
+}
+
+// UnregisterAlertmanager removes an Alertmanager instance by name, so it's
+// no longer pulled from or proxied to. It's safe to call while pulls are in
+// flight against other upstreams.
+func UnregisterAlertmanager(name string) error {
+	upstreamsLock.Lock()
+	defer upstreamsLock.Unlock()
+	return unregisterAlertmanagerLocked(name)
+}
+
+func unregisterAlertmanagerLocked(name string) error {
+	am, found := upstreams[name]
+	if !found {
+		return fmt.Errorf("Alertmanager upstream '%s' doesn't exist", name)
+	}
+	delete(upstreams, name)
+	unregisterClusterMember(am)
+	log.Infof("[%s] Removed Alertmanager source at %s", am.Name, am.URI)
+	return nil
+}
+
+// ReplaceAlertmanagers atomically swaps the entire set of configured
+// Alertmanager upstreams for a new one, used by the SIGHUP config reload and
+// the admin API to apply configuration changes without ever leaving
+// GetAlertmanagers() looking at a partially updated registry.
+func ReplaceAlertmanagers(ams []*Alertmanager) error {
+	upstreamsLock.Lock()
+	defer upstreamsLock.Unlock()
+
+	seenNames := map[string]bool{}
+	seenURIs := map[string]string{}
+	for _, am := range ams {
+		if seenNames[am.Name] {
+			return fmt.Errorf("Alertmanager upstream '%s' already exist", am.Name)
+		}
+		if existingName, found := seenURIs[am.URI]; found {
+			return fmt.Errorf("Alertmanager upstream '%s' already collects from '%s'", existingName, am.URI)
+		}
+		seenNames[am.Name] = true
+		seenURIs[am.URI] = am.Name
+	}
+
+	for _, am := range upstreams {
+		unregisterClusterMember(am)
+	}
+	upstreams = map[string]*Alertmanager{}
+	for _, am := range ams {
+		upstreams[am.Name] = am
+		registerClusterMember(am)
+		log.Infof("[%s] Configured Alertmanager source at %s (proxied: %v)", am.Name, am.URI, am.ProxyRequests)
+	}
 	return nil
 }
 
-// GetAlertmanagers returns a list of all defined Alertmanager instances
+// GetAlertmanagers returns a snapshot slice of all defined Alertmanager
+// instances. Since it's taken under a read lock it's safe to call while a
+// SIGHUP reload or admin API call is mutating the registry concurrently.
 func GetAlertmanagers() []*Alertmanager {
-	ams := []*Alertmanager{}
+	upstreamsLock.RLock()
+	defer upstreamsLock.RUnlock()
+	ams := make([]*Alertmanager, 0, len(upstreams))
 	for _, am := range upstreams {
 		ams = append(ams, am)
 	}
@@ -83,6 +179,8 @@ func GetAlertmanagers() []*Alertmanager {
 // GetAlertmanagerByName returns an instance of Alertmanager by name or nil
 // if not found
 func GetAlertmanagerByName(name string) *Alertmanager {
+	upstreamsLock.RLock()
+	defer upstreamsLock.RUnlock()
 	am, found := upstreams[name]
 	if found {
 		return am